@@ -0,0 +1,112 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+// NewAhoCorasick builds the classical Aho–Corasick automaton[9] matching
+// any of patterns. The "goto" trie is built first, by inserting every
+// pattern as a chain of edges from the start state; failure links are then
+// computed by a BFS over the trie and immediately used to fill in every
+// missing (state, symbol) transition, so the result has no ε or "failure"
+// edges at all: it is already a complete DFA over the alphabet used by
+// patterns and can be driven directly, one symbol at a time from the start
+// state, without any extra logic at match time.
+//
+// A state accepting pattern i has i in its MatchedPatterns; a state at
+// which several patterns end simultaneously (because one is a suffix of
+// another, found via the dictionary suffix chain) has all of their indices
+// there, and IsAccepting set with AcceptRule holding the lowest of them.
+//
+//	[9]: http://dl.acm.org/citation.cfm?id=360855
+func NewAhoCorasick(patterns [][]int) *NFA {
+	n := NewNFA()
+	root := n.NewState()
+	n.SetStart(root)
+
+	alphabet := map[int]struct{}{}
+	for _, pat := range patterns {
+		cur := root
+		for _, sym := range pat {
+			alphabet[sym] = struct{}{}
+			child, ok := trieChild(cur, sym)
+			if !ok {
+				child = n.NewState()
+				cur.NewEdge(sym, child)
+			}
+			cur = child
+		}
+	}
+
+	for id, pat := range patterns {
+		cur := root
+		for _, sym := range pat {
+			cur, _ = trieChild(cur, sym)
+		}
+		accept(cur, id)
+	}
+
+	// Complete root's transition table: an undefined symbol at the root
+	// always loops back to the root.
+	for sym := range alphabet {
+		if _, ok := trieChild(root, sym); !ok {
+			root.NewEdge(sym, root)
+		}
+	}
+
+	// BFS over the trie, computing each node's failure link from its
+	// (already resolved, since strictly shallower) parent's, and using
+	// it right away to complete the node's own transition table.
+	fail := map[*State]*State{root: root}
+	queue := make([]*State, 0, len(alphabet))
+	for sym := range alphabet {
+		if child, ok := trieChild(root, sym); ok && child != root {
+			fail[child] = root
+			queue = append(queue, child)
+		}
+	}
+
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		fr := fail[r]
+		for sym := range alphabet {
+			child, ok := trieChild(r, sym)
+			if !ok {
+				tgt, _ := trieChild(fr, sym)
+				r.NewEdge(sym, tgt)
+				continue
+			}
+
+			tgt, _ := trieChild(fr, sym)
+			fail[child] = tgt
+			if tgt.IsAccepting {
+				for _, id := range tgt.MatchedPatterns {
+					accept(child, id)
+				}
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return n
+}
+
+// trieChild returns the (unique, since the trie is being built as a
+// deterministic automaton) state reached from s on sym, if any.
+func trieChild(s *State, sym int) (*State, bool) {
+	for _, st := range s.Transitions().Get(sym).List() {
+		return st, true
+	}
+	return nil, false
+}
+
+// accept marks s as accepting pattern id, keeping AcceptRule as the lowest
+// id seen so far.
+func accept(s *State, id int) {
+	if !s.IsAccepting || id < s.AcceptRule {
+		s.AcceptRule = id
+	}
+	s.IsAccepting = true
+	s.MatchedPatterns = append(s.MatchedPatterns, id)
+}
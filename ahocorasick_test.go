@@ -0,0 +1,50 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func ints(s string) []int {
+	r := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		r[i] = int(s[i])
+	}
+	return r
+}
+
+// TestAhoCorasick reproduces the textbook example: patterns he, she, his,
+// hers over the text "ushers", which matches "she" and "he" ending at
+// position 4 and "hers" ending at position 6.
+func TestAhoCorasick(t *testing.T) {
+	patterns := [][]int{ints("he"), ints("she"), ints("his"), ints("hers")}
+	n := NewAhoCorasick(patterns)
+
+	got := map[int][]int{} // end position (1-based) -> matched pattern ids
+	cur := n.Start()
+	for i, sym := range ints("ushers") {
+		// Symbols outside the patterns' alphabet (like 'u' here) have
+		// no transition at all; treat that like any real matcher
+		// would, as "no match in progress", ie. back at the start.
+		if next, ok := trieChild(cur, sym); ok {
+			cur = next
+		} else {
+			cur = n.Start()
+		}
+		if cur.IsAccepting {
+			ids := append([]int(nil), cur.MatchedPatterns...)
+			sort.Ints(ids)
+			got[i+1] = ids
+		}
+	}
+
+	want := map[int][]int{4: {0, 1}, 6: {3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -64,8 +64,7 @@ func ExampleNFA_Powerset() {
 	// 	0 -> [2]
 	// 	1 -> [4]
 	// [4]
-	// 	0 -> [4]
-	// 	1 -> [4]
+	// 	0-1 -> [4]
 }
 
 func ExampleNFA_Powerset_complexity() {
@@ -237,21 +236,16 @@ func ExampleNFA_MinimalDFA() {
 	// 	1 -> [1]
 	// 	2 -> [4]
 	// [1]
-	// 	0 -> [4]
-	// 	1 -> [4]
 	// 	2 -> [2]
+	// 	0-1 -> [4]
 	// [[2]]
-	// 	0 -> [4]
-	// 	1 -> [4]
-	// 	2 -> [4]
+	// 	0-2 -> [4]
 	// [3]
 	// 	0 -> [4]
 	// 	1 -> [1]
 	// 	2 -> [2]
 	// [4]
-	// 	0 -> [4]
-	// 	1 -> [4]
-	// 	2 -> [4]
+	// 	0-2 -> [4]
 }
 
 func TestEpsilon(t *testing.T) {
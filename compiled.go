@@ -0,0 +1,248 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CompiledDFA is a flat, table-driven form of a DFA (an NFA produced by
+// Powerset or MinimalDFA), suitable for matching without walking maps per
+// byte. Transitions are indexed by byte class rather than by raw symbol
+// (see ByteClasses), so the table stays small even for NFAs built over a
+// large alphabet.
+type CompiledDFA struct {
+	Classes   ByteClasses
+	NumStates int
+	Start     int32
+
+	// Table holds NumStates*Classes.N entries; Table[state*Classes.N+class]
+	// is the successor state, or -1 if there is none.
+	Table []int32
+
+	// Accepts holds one entry per state: its AcceptRule if the state is
+	// accepting, or -1 otherwise.
+	Accepts []int32
+}
+
+// Compile converts n, assumed to already be a DFA (e.g. the result of
+// Powerset or MinimalDFA), into a CompiledDFA. Symbols are classified by
+// ByteClasses(max), so max should be 255 for byte-oriented machines or
+// 0x10FFFF for rune-oriented ones.
+func (n *NFA) Compile(max int) *CompiledDFA {
+	classes := n.ByteClasses(max)
+	rep := make([]int, classes.N)
+	seen := make([]bool, classes.N)
+	for sym := 0; sym <= max; sym++ {
+		c := classes.Classes[sym]
+		if !seen[c] {
+			seen[c] = true
+			rep[c] = sym
+		}
+	}
+
+	states := n.List()
+	table := make([]int32, len(states)*classes.N)
+	accepts := make([]int32, len(states))
+	for i, s := range states {
+		if s.IsAccepting {
+			accepts[i] = int32(s.AcceptRule)
+		} else {
+			accepts[i] = -1
+		}
+		for c := 0; c < classes.N; c++ {
+			idx := i*classes.N + c
+			if next := step(s, rep[c]); next != nil {
+				table[idx] = int32(next.Id())
+			} else {
+				table[idx] = -1
+			}
+		}
+	}
+
+	return &CompiledDFA{
+		Classes:   classes,
+		NumStates: len(states),
+		Start:     int32(n.Start().Id()),
+		Table:     table,
+		Accepts:   accepts,
+	}
+}
+
+func (d *CompiledDFA) next(state int32, sym int) int32 {
+	c := d.Classes.Class(sym)
+	if c < 0 {
+		return -1
+	}
+	return d.Table[int(state)*d.Classes.N+c]
+}
+
+// Match reports whether the DFA accepts the whole of input. On success end
+// is len(input) and rule is the AcceptRule of the state the match ended in;
+// on failure end is the offset of the first byte for which there was no
+// transition, or len(input) if every byte had one but the final state
+// wasn't accepting.
+func (d *CompiledDFA) Match(input []byte) (end int, rule int, ok bool) {
+	s := d.Start
+	for i, b := range input {
+		next := d.next(s, int(b))
+		if next < 0 {
+			return i, -1, false
+		}
+		s = next
+	}
+	if a := d.Accepts[s]; a >= 0 {
+		return len(input), int(a), true
+	}
+	return len(input), -1, false
+}
+
+// LongestMatch scans input left to right and returns the end offset and
+// rule of the longest prefix accepted by the DFA. ok is false if no
+// non-empty prefix is accepted.
+func (d *CompiledDFA) LongestMatch(input []byte) (end int, rule int, ok bool) {
+	s := d.Start
+	bestEnd, bestRule := -1, -1
+	for i, b := range input {
+		next := d.next(s, int(b))
+		if next < 0 {
+			break
+		}
+
+		s = next
+		if a := d.Accepts[s]; a >= 0 {
+			bestEnd, bestRule = i+1, int(a)
+		}
+	}
+	if bestEnd < 0 {
+		return 0, -1, false
+	}
+	return bestEnd, bestRule, true
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (d *CompiledDFA) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, v := range []int32{
+		int32(d.Classes.Max),
+		int32(d.Classes.N),
+		int32(d.NumStates),
+		d.Start,
+	} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range d.Classes.Classes {
+		if err := binary.Write(&buf, binary.LittleEndian, int32(c)); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, d.Table); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, d.Accepts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (d *CompiledDFA) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var max, numClasses, numStates, start int32
+	for _, v := range []*int32{&max, &numClasses, &numStates, &start} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	classes := make([]int32, max+1)
+	if err := binary.Read(r, binary.LittleEndian, classes); err != nil {
+		return err
+	}
+	classesInt := make([]int, len(classes))
+	for i, c := range classes {
+		classesInt[i] = int(c)
+	}
+
+	table := make([]int32, int(numStates)*int(numClasses))
+	if err := binary.Read(r, binary.LittleEndian, table); err != nil {
+		return err
+	}
+
+	accepts := make([]int32, numStates)
+	if err := binary.Read(r, binary.LittleEndian, accepts); err != nil {
+		return err
+	}
+
+	d.Classes = ByteClasses{Max: int(max), Classes: classesInt, N: int(numClasses)}
+	d.NumStates = int(numStates)
+	d.Start = start
+	d.Table = table
+	d.Accepts = accepts
+	return nil
+}
+
+// WriteGoSource writes a Go source file declaring package pkg with the
+// tables of d as package-level vars prefixed by name, for zero-cost
+// embedding of a recognizer built offline.
+func (d *CompiledDFA) WriteGoSource(w io.Writer, pkg, name string) error {
+	if _, err := fmt.Fprintf(w, "// Code generated by fsm.CompiledDFA.WriteGoSource. DO NOT EDIT.\n\npackage %s\n\n", pkg); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(
+		w,
+		"const (\n\t%sMax        = %d\n\t%sNumClasses = %d\n\t%sNumStates  = %d\n\t%sStart      = %d\n)\n\n",
+		name, d.Classes.Max, name, d.Classes.N, name, d.NumStates, name, d.Start,
+	); err != nil {
+		return err
+	}
+
+	if err := writeIntVar(w, name+"Classes", d.Classes.Classes); err != nil {
+		return err
+	}
+	if err := writeInt32Var(w, name+"Table", d.Table); err != nil {
+		return err
+	}
+	return writeInt32Var(w, name+"Accepts", d.Accepts)
+}
+
+func writeIntVar(w io.Writer, name string, vals []int) error {
+	if _, err := fmt.Fprintf(w, "var %s = []int{", name); err != nil {
+		return err
+	}
+	for i, v := range vals {
+		sep := ", "
+		if i == 0 {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s%d", sep, v); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n\n")
+	return err
+}
+
+func writeInt32Var(w io.Writer, name string, vals []int32) error {
+	if _, err := fmt.Fprintf(w, "var %s = []int32{", name); err != nil {
+		return err
+	}
+	for i, v := range vals {
+		sep := ", "
+		if i == 0 {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s%d", sep, v); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n\n")
+	return err
+}
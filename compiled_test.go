@@ -0,0 +1,69 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCompiledDFAMatch(t *testing.T) {
+	cat := linear("cat")
+	dog := linear("dog")
+	dfa := cat.Union(dog).Powerset(false).Compile(255)
+
+	if end, rule, ok := dfa.Match([]byte("cat")); !ok || end != 3 || rule != 0 {
+		t.Fatalf("Match(cat) = %d, %d, %v", end, rule, ok)
+	}
+	if end, rule, ok := dfa.Match([]byte("dog")); !ok || end != 3 || rule != 1 {
+		t.Fatalf("Match(dog) = %d, %d, %v", end, rule, ok)
+	}
+	if _, _, ok := dfa.Match([]byte("cats")); ok {
+		t.Fatal("Match(cats): expected no match")
+	}
+	if _, _, ok := dfa.Match([]byte("ca")); ok {
+		t.Fatal("Match(ca): expected no match")
+	}
+}
+
+func TestCompiledDFALongestMatch(t *testing.T) {
+	dfa := linear("cat").Powerset(false).Compile(255)
+
+	if end, rule, ok := dfa.LongestMatch([]byte("cats")); !ok || end != 3 || rule != 0 {
+		t.Fatalf("LongestMatch(cats) = %d, %d, %v", end, rule, ok)
+	}
+	if _, _, ok := dfa.LongestMatch([]byte("dog")); ok {
+		t.Fatal("LongestMatch(dog): expected no match")
+	}
+}
+
+func TestCompiledDFABinaryRoundTrip(t *testing.T) {
+	dfa := linear("cat").Powerset(false).Compile(255)
+	data, err := dfa.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got CompiledDFA
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if end, rule, ok := got.Match([]byte("cat")); !ok || end != 3 || rule != 0 {
+		t.Fatalf("round-tripped Match(cat) = %d, %d, %v", end, rule, ok)
+	}
+}
+
+func TestCompiledDFAWriteGoSource(t *testing.T) {
+	dfa := linear("cat").Powerset(false).Compile(255)
+	var buf bytes.Buffer
+	if err := dfa.WriteGoSource(&buf, "lex", "cat"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "cat.go", buf.Bytes(), 0); err != nil {
+		t.Fatalf("generated source doesn't parse: %v\n%s", err, buf.Bytes())
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ----------------------------------------------------------------------- DOT
+
+// DOTOptions configures WriteDOT. The zero value selects reasonable
+// defaults.
+type DOTOptions struct {
+	// Name is the digraph's name. The zero value uses "fsm".
+	Name string
+	// RankDir is Graphviz's rankdir attribute (e.g. "LR" or "TB"). The
+	// zero value leaves it unset, so Graphviz uses its own default
+	// ("TB").
+	RankDir string
+}
+
+// WriteDOT writes n as a Graphviz digraph[9]: the start state is marked by
+// an arrow from an invisible node, accepting states are drawn as double
+// circles, ε edges (including those added through PriorityEpsilon) are
+// dashed, and every group of edges sharing a (from, to) pair is collapsed
+// into a single label such as "0,2,5-9" (see RangeSet.String).
+//
+//	[9]: http://www.graphviz.org/doc/info/lang.html
+func (n *NFA) WriteDOT(w io.Writer, opts *DOTOptions) error {
+	if opts == nil {
+		opts = &DOTOptions{}
+	}
+	name := opts.Name
+	if name == "" {
+		name = "fsm"
+	}
+
+	write := func(format string, args ...interface{}) error {
+		_, err := fmt.Fprintf(w, format, args...)
+		return err
+	}
+
+	if err := write("digraph %s {\n", name); err != nil {
+		return err
+	}
+	if opts.RankDir != "" {
+		if err := write("\trankdir=%s;\n", opts.RankDir); err != nil {
+			return err
+		}
+	}
+	if err := write("\t__start__ [shape=point style=invis];\n"); err != nil {
+		return err
+	}
+	if err := write("\t__start__ -> %d;\n", n.Start().Id()); err != nil {
+		return err
+	}
+
+	states := n.List()
+	for _, s := range states {
+		shape := "circle"
+		if s.IsAccepting {
+			shape = "doublecircle"
+		}
+		if err := write("\t%d [shape=%s];\n", s.Id(), shape); err != nil {
+			return err
+		}
+	}
+
+	type key struct{ from, to int }
+	var litOrder, epsOrder []key
+	lits := map[key]RangeSet{}
+	eps := map[key]bool{}
+
+	for _, s := range states {
+		for sym, nextStates := range s.transitions() {
+			for next := range nextStates {
+				k := key{s.Id(), next.Id()}
+				if sym <= Epsilon {
+					if !eps[k] {
+						eps[k] = true
+						epsOrder = append(epsOrder, k)
+					}
+					continue
+				}
+				if _, ok := lits[k]; !ok {
+					litOrder = append(litOrder, k)
+				}
+				lits[k] = lits[k].Add(sym, sym)
+			}
+		}
+		for _, re := range s.ranges {
+			k := key{s.Id(), re.next.Id()}
+			if _, ok := lits[k]; !ok {
+				litOrder = append(litOrder, k)
+			}
+			for _, r := range re.ranges {
+				lits[k] = lits[k].Add(r.Lo, r.Hi)
+			}
+		}
+	}
+
+	byFromTo := func(a, b []key) func(i, j int) bool {
+		return func(i, j int) bool {
+			if a[i].from != a[j].from {
+				return a[i].from < a[j].from
+			}
+			return a[i].to < a[j].to
+		}
+	}
+	sort.Slice(litOrder, byFromTo(litOrder, litOrder))
+	sort.Slice(epsOrder, byFromTo(epsOrder, epsOrder))
+
+	for _, k := range litOrder {
+		if err := write("\t%d -> %d [label=%q];\n", k.from, k.to, lits[k].String()); err != nil {
+			return err
+		}
+	}
+	for _, k := range epsOrder {
+		if err := write("\t%d -> %d [label=%q style=dashed];\n", k.from, k.to, "ε"); err != nil {
+			return err
+		}
+	}
+
+	return write("}\n")
+}
+
+// ---------------------------------------------------------------------- JSON
+
+// jsonEdge is the wire format of one symbol's outgoing edges, states
+// referenced by Id().
+type jsonEdge struct {
+	Sym int   `json:"sym"`
+	To  []int `json:"to"`
+}
+
+// jsonRangeEdge is the wire format of one range edge, its target referenced
+// by Id().
+type jsonRangeEdge struct {
+	Ranges RangeSet `json:"ranges"`
+	To     int      `json:"to"`
+}
+
+// jsonState is the wire format of a single State.
+type jsonState struct {
+	Id              int             `json:"id"`
+	IsAccepting     bool            `json:"isAccepting,omitempty"`
+	AcceptRule      int             `json:"acceptRule,omitempty"`
+	AcceptPriority  int             `json:"acceptPriority,omitempty"`
+	MatchedPatterns []int           `json:"matchedPatterns,omitempty"`
+	Edges           []jsonEdge      `json:"edges,omitempty"`
+	Ranges          []jsonRangeEdge `json:"ranges,omitempty"`
+}
+
+// jsonNFA is the wire format of a whole NFA.
+type jsonNFA struct {
+	Start  int         `json:"start"`
+	States []jsonState `json:"states"`
+}
+
+func (s *State) toJSON() jsonState {
+	js := jsonState{
+		Id:              s.Id(),
+		IsAccepting:     s.IsAccepting,
+		AcceptRule:      s.AcceptRule,
+		AcceptPriority:  s.AcceptPriority,
+		MatchedPatterns: s.MatchedPatterns,
+	}
+
+	var syms sort.IntSlice
+	for sym := range s.transitions() {
+		syms = append(syms, sym)
+	}
+	sort.Sort(syms)
+	for _, sym := range syms {
+		var to []int
+		for next := range s.transitions()[sym] {
+			to = append(to, next.Id())
+		}
+		sort.Ints(to)
+		js.Edges = append(js.Edges, jsonEdge{sym, to})
+	}
+
+	for _, re := range s.ranges {
+		js.Ranges = append(js.Ranges, jsonRangeEdge{re.ranges, re.next.Id()})
+	}
+	return js
+}
+
+// MarshalJSON implements json.Marshaler. Edges reference their targets by
+// Id(); see NFA.UnmarshalJSON for the supported way to get them back.
+func (s *State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the scalar fields of s
+// (IsAccepting, AcceptRule, AcceptPriority, MatchedPatterns). Edges
+// reference sibling states by pointer, which only make sense resolved
+// against a parent NFA, so decoding JSON carrying any is an error; use
+// NFA.UnmarshalJSON to rebuild a whole machine instead.
+func (s *State) UnmarshalJSON(data []byte) error {
+	var js jsonState
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if len(js.Edges) != 0 || len(js.Ranges) != 0 {
+		return fmt.Errorf("fsm: State.UnmarshalJSON: edges can't be resolved in isolation, use NFA.UnmarshalJSON")
+	}
+
+	s.IsAccepting = js.IsAccepting
+	s.AcceptRule = js.AcceptRule
+	s.AcceptPriority = js.AcceptPriority
+	s.MatchedPatterns = js.MatchedPatterns
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding t as a list of {sym, to}
+// pairs sorted by sym, states referenced by Id().
+func (t Transitions) MarshalJSON() ([]byte, error) {
+	var syms sort.IntSlice
+	for sym := range t.transitions {
+		syms = append(syms, sym)
+	}
+	sort.Sort(syms)
+
+	edges := make([]jsonEdge, 0, len(syms))
+	for _, sym := range syms {
+		var to []int
+		for next := range t.transitions[sym] {
+			to = append(to, next.Id())
+		}
+		sort.Ints(to)
+		edges = append(edges, jsonEdge{sym, to})
+	}
+	return json.Marshal(edges)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A Transitions value's closures
+// hold pointers to sibling states, which only make sense resolved against a
+// parent NFA, so this always fails; use NFA.UnmarshalJSON instead.
+func (t *Transitions) UnmarshalJSON(data []byte) error {
+	return fmt.Errorf("fsm: Transitions.UnmarshalJSON is not supported, use NFA.UnmarshalJSON")
+}
+
+// MarshalJSON implements json.Marshaler, encoding every state of n plus
+// which one is the start state.
+func (n *NFA) MarshalJSON() ([]byte, error) {
+	jn := jsonNFA{Start: n.Start().Id()}
+	for _, s := range n.List() {
+		jn.States = append(jn.States, s.toJSON())
+	}
+	return json.Marshal(jn)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the counterpart to MarshalJSON.
+// It replaces n's contents entirely.
+func (n *NFA) UnmarshalJSON(data []byte) error {
+	var jn jsonNFA
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return err
+	}
+
+	n.s2i = map[*State]int{}
+	n.i2s = map[int]*State{}
+	n.start = nil
+
+	states := make([]*State, len(jn.States))
+	for i := range states {
+		states[i] = n.NewState()
+	}
+
+	for _, js := range jn.States {
+		if js.Id < 0 || js.Id >= len(states) {
+			return fmt.Errorf("fsm: NFA.UnmarshalJSON: state id %d out of range", js.Id)
+		}
+
+		s := states[js.Id]
+		s.IsAccepting = js.IsAccepting
+		s.AcceptRule = js.AcceptRule
+		s.AcceptPriority = js.AcceptPriority
+		s.MatchedPatterns = js.MatchedPatterns
+		for _, e := range js.Edges {
+			for _, to := range e.To {
+				s.NewEdge(e.Sym, states[to])
+			}
+		}
+		for _, re := range js.Ranges {
+			s.NewRangeEdge(re.Ranges, states[re.To])
+		}
+	}
+
+	if len(states) > 0 {
+		if jn.Start < 0 || jn.Start >= len(states) {
+			return fmt.Errorf("fsm: NFA.UnmarshalJSON: start id %d out of range", jn.Start)
+		}
+		n.SetStart(states[jn.Start])
+	}
+	return nil
+}
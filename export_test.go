@@ -0,0 +1,110 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	n := linear("ab")
+	var buf bytes.Buffer
+	if err := n.WriteDOT(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"digraph fsm {",
+		"__start__ -> 0;",
+		"doublecircle",
+		`0 -> 1 [label="97"];`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteDOTCollapsesLabels(t *testing.T) {
+	n := NewNFA()
+	s0, s1 := n.NewState(), n.NewState()
+	n.SetStart(s0)
+	s1.IsAccepting = true
+	// 0 and 1 are adjacent and merge into one range; 3-7 stay separate
+	// from them but merge with each other, matching RangeSet.Add.
+	for _, sym := range []int{0, 1, 3, 4, 5, 6, 7} {
+		s0.NewEdge(sym, s1)
+	}
+
+	var buf bytes.Buffer
+	if err := n.WriteDOT(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := buf.String(), `0 -> 1 [label="0-1,3-7"];`; !strings.Contains(g, w) {
+		t.Fatalf("expected collapsed label %q, got:\n%s", w, g)
+	}
+}
+
+func TestWriteDOTDashedEpsilon(t *testing.T) {
+	n := NewNFA()
+	s0, s1 := n.NewState(), n.NewState()
+	n.SetStart(s0)
+	s0.NewEdge(Epsilon, s1)
+
+	var buf bytes.Buffer
+	if err := n.WriteDOT(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := buf.String(), "style=dashed"; !strings.Contains(g, w) {
+		t.Fatalf("expected dashed epsilon edge, got:\n%s", g)
+	}
+}
+
+func TestNFAJSONRoundTrip(t *testing.T) {
+	orig := linear("cat").Union(linear("dog")).Powerset(true)
+
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got NFA
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"cat", "dog"} {
+		want := runDFA(orig.Start(), s)
+		have := runDFA(got.Start(), s)
+		if want == nil || have == nil {
+			t.Fatalf("%q: nil state (want=%v have=%v)", s, want, have)
+		}
+		if want.IsAccepting != have.IsAccepting || want.AcceptRule != have.AcceptRule {
+			t.Fatalf("%q: accepting/rule mismatch: want %v/%d, got %v/%d", s, want.IsAccepting, want.AcceptRule, have.IsAccepting, have.AcceptRule)
+		}
+	}
+	if g, e := got.Len(), orig.Len(); g != e {
+		t.Fatalf("Len() = %d, want %d", g, e)
+	}
+}
+
+func TestStateJSONUnmarshalRejectsEdges(t *testing.T) {
+	var s State
+	err := json.Unmarshal([]byte(`{"id":0,"edges":[{"sym":1,"to":[2]}]}`), &s)
+	if err == nil {
+		t.Fatal("expected an error when edges are present")
+	}
+}
+
+func TestTransitionsJSONUnmarshalUnsupported(t *testing.T) {
+	var tr Transitions
+	if err := json.Unmarshal([]byte(`[]`), &tr); err == nil {
+		t.Fatal("expected an error")
+	}
+}
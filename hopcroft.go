@@ -0,0 +1,309 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import "sort"
+
+// HopcroftMinimize returns n converted to a minimal DFA, like MinimalDFA,
+// but using Hopcroft's O(m·log n) partition-refinement algorithm[10]
+// instead of Brzozowski's double-reversal one. Brzozowski is elegant but
+// its intermediate Powerset pass is O(2^n) worst case; on large lexer NFAs
+// Hopcroft is dramatically faster and its running time is predictable.
+//
+// n need not already be a DFA: it is converted with Powerset(true) first,
+// to obtain the complete transition function the algorithm requires. Dead
+// state is possibly present in the result if withDeadState == true.
+//
+// Two accepting states are only merged if they accept the same rule (see
+// State.AcceptRule): this matters for NFAs built through Union, where
+// states accepting different patterns must stay distinguishable even if
+// otherwise language-equivalent. Unlike MinimalDFA, AcceptRule and
+// AcceptPriority on the result are meaningful, so this is the minimizer to
+// use for Union-built, multi-rule NFAs.
+//
+//	[10]: http://en.wikipedia.org/wiki/DFA_minimization#Hopcroft.27s_algorithm
+func (n *NFA) HopcroftMinimize(withDeadState bool) *NFA {
+	d := n.Powerset(true)
+	states := d.List()
+	numStates := len(states)
+	out := NewNFA()
+	if numStates == 0 {
+		return out
+	}
+
+	max := -1
+	for _, s := range states {
+		for sym := range s.transitions() {
+			if sym > max {
+				max = sym
+			}
+		}
+		for _, re := range s.ranges {
+			if h := re.ranges.hi(); h > max {
+				max = h
+			}
+		}
+	}
+
+	var classes ByteClasses
+	if max >= 0 {
+		classes = d.ByteClasses(max)
+	}
+
+	// classRange[c] is the contiguous symbol interval classes.Class maps
+	// to c; rep[c] is one symbol in it, used to probe transitions.
+	classRange := make([]Range, classes.N)
+	rep := make([]int, classes.N)
+	if classes.N > 0 {
+		lo, cur := 0, classes.Classes[0]
+		for sym := 1; sym <= max; sym++ {
+			if classes.Classes[sym] != cur {
+				classRange[cur] = Range{lo, sym - 1}
+				lo, cur = sym, classes.Classes[sym]
+			}
+		}
+		classRange[cur] = Range{lo, max}
+		for c, r := range classRange {
+			rep[c] = r.Lo
+		}
+	}
+
+	delta := make([][]int32, numStates)
+	for i, s := range states {
+		delta[i] = make([]int32, classes.N)
+		for c := 0; c < classes.N; c++ {
+			if next := step(s, rep[c]); next != nil {
+				delta[i][c] = int32(next.Id())
+			} else {
+				delta[i][c] = -1
+			}
+		}
+	}
+
+	// pred[c][t] lists every state q with delta[q][c] == t, so a split
+	// step can walk straight to the preimage of the splitting set
+	// instead of scanning every state.
+	pred := make([][][]int32, classes.N)
+	for c := range pred {
+		pred[c] = make([][]int32, numStates)
+	}
+	for q := 0; q < numStates; q++ {
+		for c := 0; c < classes.N; c++ {
+			if t := delta[q][c]; t >= 0 {
+				pred[c][t] = append(pred[c][t], int32(q))
+			}
+		}
+	}
+
+	// Initial partition: one block per distinct AcceptRule among
+	// accepting states, plus one for non-accepting states.
+	buckets := map[int][]int{}
+	const nonAcceptKey = -1
+	for i, s := range states {
+		key := nonAcceptKey
+		if s.IsAccepting {
+			key = s.AcceptRule + 1
+		}
+		buckets[key] = append(buckets[key], i)
+	}
+
+	partitions := map[int][]int{}
+	setOf := make([]int, numStates)
+	nextSetID := 0
+	newSet := func(members []int) int {
+		id := nextSetID
+		nextSetID++
+		partitions[id] = members
+		for _, q := range members {
+			setOf[q] = id
+		}
+		return id
+	}
+
+	var initial []int
+	largest := -1
+	for _, members := range buckets {
+		id := newSet(members)
+		initial = append(initial, id)
+		if largest < 0 || len(partitions[id]) > len(partitions[largest]) {
+			largest = id
+		}
+	}
+
+	inWL := map[int]bool{}
+	var worklist []int
+	push := func(id int) {
+		if !inWL[id] {
+			inWL[id] = true
+			worklist = append(worklist, id)
+		}
+	}
+	for _, id := range initial {
+		if id != largest {
+			push(id)
+		}
+	}
+
+	for len(worklist) > 0 {
+		a := worklist[0]
+		worklist = worklist[1:]
+		inWL[a] = false
+
+		for c := 0; c < classes.N; c++ {
+			var xList []int
+			for _, q := range partitions[a] {
+				for _, p := range pred[c][q] {
+					xList = append(xList, int(p))
+				}
+			}
+			if len(xList) == 0 {
+				continue
+			}
+
+			xMember := map[int]bool{}
+			affected := map[int][]int{}
+			for _, q := range xList {
+				xMember[q] = true
+				affected[setOf[q]] = append(affected[setOf[q]], q)
+			}
+
+			for y, inter := range affected {
+				ySet := partitions[y]
+				if len(inter) == len(ySet) {
+					continue
+				}
+
+				var rest []int
+				for _, q := range ySet {
+					if !xMember[q] {
+						rest = append(rest, q)
+					}
+				}
+				partitions[y] = rest
+				for _, q := range rest {
+					setOf[q] = y
+				}
+				newID := newSet(inter)
+
+				if inWL[y] {
+					push(newID)
+					continue
+				}
+				if len(inter) <= len(rest) {
+					push(newID)
+				} else {
+					push(y)
+				}
+			}
+		}
+	}
+
+	// Read the final partition off setOf rather than the partitions map:
+	// simpler to reason about and immune to any bookkeeping drift above.
+	final := map[int][]int{}
+	for i := 0; i < numStates; i++ {
+		final[setOf[i]] = append(final[setOf[i]], i)
+	}
+
+	// Identify the dead class, if any, so it can be dropped when the
+	// caller doesn't want one: non-accepting and self-looping on every
+	// symbol.
+	deadClass := -1
+	for id, members := range final {
+		s := states[members[0]]
+		if s.IsAccepting {
+			continue
+		}
+
+		dead := true
+		for c := 0; c < classes.N; c++ {
+			if t := delta[members[0]][c]; t < 0 || setOf[int(t)] != id {
+				dead = false
+				break
+			}
+		}
+		if dead {
+			deadClass = id
+			break
+		}
+	}
+
+	// The start state's own class is never dropped, even if it happens
+	// to be the dead class: a DFA must always have a start state, and an
+	// NFA whose language is empty (e.g. a lone non-accepting state with
+	// no edges) is exactly such a case, distinct from the synthetic dead
+	// state Powerset(true) adds to otherwise-incomplete DFAs.
+	startClass := setOf[d.Start().Id()]
+
+	type class struct {
+		id      int
+		members []int
+	}
+	var order []class
+	for id, members := range final {
+		if !withDeadState && id == deadClass && id != startClass {
+			continue
+		}
+		order = append(order, class{id, members})
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return minInt(order[i].members) < minInt(order[j].members)
+	})
+
+	outStates := map[int]*State{}
+	for _, c := range order {
+		outStates[c.id] = out.NewState()
+	}
+
+	for _, c := range order {
+		ns := outStates[c.id]
+		haveAccept := false
+		for _, m := range c.members {
+			s := states[m]
+			if !s.IsAccepting {
+				continue
+			}
+			if !haveAccept || s.AcceptPriority < ns.AcceptPriority {
+				ns.AcceptPriority = s.AcceptPriority
+				ns.AcceptRule = s.AcceptRule
+			}
+			haveAccept = true
+			ns.IsAccepting = true
+		}
+
+		rep0 := c.members[0]
+		for cc := 0; cc < classes.N; cc++ {
+			t := delta[rep0][cc]
+			if t < 0 {
+				continue
+			}
+
+			tid := setOf[int(t)]
+			if !withDeadState && tid == deadClass {
+				continue
+			}
+
+			r := classRange[cc]
+			if r.Lo == r.Hi {
+				ns.NewEdge(r.Lo, outStates[tid])
+			} else {
+				ns.NewRangeEdge(NewRangeSet(r), outStates[tid])
+			}
+		}
+	}
+
+	out.SetStart(outStates[setOf[d.Start().Id()]])
+	return out
+}
+
+func minInt(a []int) int {
+	m := a[0]
+	for _, v := range a[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
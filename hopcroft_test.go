@@ -0,0 +1,91 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import "testing"
+
+func TestHopcroftMinimize(t *testing.T) {
+	// (a|b)*abb, the textbook example with a well-known minimal DFA of 4
+	// states (dead state aside).
+	n := NewNFA()
+	s0 := n.NewState()
+	n.SetStart(s0)
+	s0.NewEdge('a', s0)
+	s0.NewEdge('b', s0)
+
+	s1 := n.NewState()
+	s0.NewEdge('a', s1)
+
+	s2 := n.NewState()
+	s1.NewEdge('b', s2)
+
+	s3 := n.NewState()
+	s3.IsAccepting = true
+	s2.NewEdge('b', s3)
+
+	got := n.HopcroftMinimize(false)
+	for _, s := range []string{"abb", "aabb", "babb", "ababb"} {
+		if end := runDFA(got.Start(), s); end == nil || !end.IsAccepting {
+			t.Fatalf("%q: expected accepted", s)
+		}
+	}
+	for _, s := range []string{"ab", "abbb", "a", ""} {
+		if end := runDFA(got.Start(), s); end != nil && end.IsAccepting {
+			t.Fatalf("%q: expected rejected", s)
+		}
+	}
+
+	if g, e := len(got.List()), 4; g != e {
+		t.Fatalf("got %d states, want %d (minimal)", g, e)
+	}
+}
+
+func TestHopcroftMinimizeAcceptRulePriority(t *testing.T) {
+	// "if" as both a keyword (rule 0) and a generic identifier (rule 1):
+	// Powerset folds both accepting states into one DFA state, and the
+	// minimized result must still report the higher-priority rule 0.
+	kw := linear("if")
+	ident := linear("if")
+	u := kw.Union(ident)
+
+	got := u.HopcroftMinimize(false)
+	end := runDFA(got.Start(), "if")
+	if end == nil || !end.IsAccepting {
+		t.Fatal("expected \"if\" to be accepted")
+	}
+	if g, e := end.AcceptRule, 0; g != e {
+		t.Fatalf("AcceptRule = %d, want %d (the higher-priority rule)", g, e)
+	}
+}
+
+func TestHopcroftMinimizeWithDeadState(t *testing.T) {
+	got := linear("cat").HopcroftMinimize(true)
+	start := got.Start()
+	if step(start, 'd') == nil {
+		t.Fatal("expected a dead-state transition to be present")
+	}
+
+	without := linear("cat").HopcroftMinimize(false)
+	if step(without.Start(), 'd') != nil {
+		t.Fatal("expected no transition without a dead state")
+	}
+}
+
+// TestHopcroftMinimizeEmptyLanguage guards against a nil-pointer panic in
+// SetStart: a lone non-accepting state with no outgoing edges is, by
+// definition, its own dead state, but it's still the NFA's (only) start
+// state and must survive even with withDeadState == false.
+func TestHopcroftMinimizeEmptyLanguage(t *testing.T) {
+	n := NewNFA()
+	n.NewState()
+
+	got := n.HopcroftMinimize(false)
+	if g, e := got.Len(), 1; g != e {
+		t.Fatalf("Len() = %d, want %d", g, e)
+	}
+	if got.Start() == nil || got.Start().IsAccepting {
+		t.Fatal("expected a single, non-accepting start state")
+	}
+}
@@ -17,10 +17,14 @@ needed and all the additional edges to it are only a waste of memory.
 
 Note: Negative symbol values are reserved for internal purposes.
 
-TODO
+Priorities
 
-Implement ε edges having other than the default priority (Epsilon == -1). This
-is needed for regexp based recognizers/tokenizers like golex[8].
+ε edges added through PriorityEpsilon carry a priority below that of the
+default, unprioritized Epsilon edge. NFA.Union uses them to stitch several
+pattern NFAs, one per lexer rule, under a fresh start state; when Powerset
+subsequently folds several accepting NFA states into one DFA state, it keeps
+the AcceptRule/AcceptPriority of the lowest-numbered (highest-priority) one,
+matching how golex/cznic-lex disambiguate between overlapping patterns[8].
 
 Links
 
@@ -142,7 +146,13 @@ func (n *NFA) List() (r []*State) {
 // MinimalDFA returns the NFA converted to a minimal DFA[5]. Dead state is
 // possibly constructed if withDeadState == true.
 //
-// Note: Algorithm used is Brzozowski[6].
+// Note: Algorithm used is Brzozowski[6]. Brzozowski's double reversal
+// collapses every accepting state down to a single boolean (is the
+// language accepted or not) along the way, so the result's AcceptRule and
+// AcceptPriority are not meaningful: do not call MinimalDFA on an NFA built
+// through Union, where those fields distinguish which lexer rule matched.
+// Use HopcroftMinimize instead; it keeps states accepting different rules
+// apart.
 func (n *NFA) MinimalDFA(withDeadState bool) *NFA {
 	return n.Reverse().Powerset(withDeadState).Reverse().Powerset(withDeadState)
 }
@@ -175,10 +185,19 @@ func (n *NFA) Powerset(withDeadState bool) (out *NFA) {
 		result = out.NewState()
 		closures[cid] = result
 		transitions := transitions{}
+		var rangeContribs []rangeContrib
+		haveAccept := false
 		for cset := range c {
-			result.IsAccepting = result.IsAccepting || cset.IsAccepting
+			if cset.IsAccepting {
+				if !haveAccept || cset.AcceptPriority < result.AcceptPriority {
+					result.AcceptPriority = cset.AcceptPriority
+					result.AcceptRule = cset.AcceptRule
+				}
+				haveAccept = true
+				result.IsAccepting = true
+			}
 			for sym, nextStates := range cset.transitions() {
-				if sym < 0 { //TODO(later) implement priorities
+				if sym < 0 {
 					continue
 				}
 
@@ -189,9 +208,36 @@ func (n *NFA) Powerset(withDeadState bool) (out *NFA) {
 					}
 				}
 			}
+			for _, re := range cset.ranges {
+				alphabetSize = mathutil.Max(alphabetSize, re.ranges.hi()+1)
+				rangeContribs = append(rangeContribs, rangeContrib{re.ranges, re.next.closure()})
+			}
 		}
-		for sym, closure := range transitions {
-			result.NewEdge(sym, f(closure))
+
+		if len(rangeContribs) == 0 {
+			for sym, closure := range transitions {
+				result.NewEdge(sym, f(closure))
+			}
+			return
+		}
+
+		// Some state in this closure carries range edges: compute the
+		// coarsest partition of the alphabet induced by every literal
+		// symbol and every range in play, and emit one (possibly
+		// range-labeled) DFA edge per partition class instead of one
+		// per raw symbol.
+		for _, p := range partition(transitions, rangeContribs) {
+			if len(p.tgt) == 0 {
+				continue
+			}
+
+			next := f(p.tgt)
+			if p.lo == p.hi {
+				result.NewEdge(p.lo, next)
+				continue
+			}
+
+			result.NewRangeEdge(NewRangeSet(Range{p.lo, p.hi}), next)
 		}
 
 		return
@@ -200,26 +246,63 @@ func (n *NFA) Powerset(withDeadState bool) (out *NFA) {
 	out.start = f(n.Start().closure())
 	var dead *State
 	if withDeadState {
-		for state := range out.s2i {
-			edges := state.transitions()
-			for sym := 0; sym < alphabetSize; sym++ {
-				if _, ok := edges[sym]; !ok {
-					if dead == nil {
-						dead = out.NewState()
-					}
-					state.NewEdge(sym, dead)
+		for _, state := range out.List() {
+			for _, g := range uncoveredRanges(state, alphabetSize) {
+				if dead == nil {
+					dead = out.NewState()
+				}
+				if g.Lo == g.Hi {
+					state.NewEdge(g.Lo, dead)
+				} else {
+					state.NewRangeEdge(NewRangeSet(g), dead)
 				}
 			}
 		}
-		if dead != nil {
-			for sym := 0; sym < alphabetSize; sym++ {
-				dead.NewEdge(sym, dead)
-			}
+		if dead != nil && alphabetSize > 0 {
+			dead.NewRangeEdge(NewRangeSet(Range{0, alphabetSize - 1}), dead)
 		}
 	}
 	return
 }
 
+// uncoveredRanges returns the gaps, as a sorted list of disjoint ranges, left
+// in [0, alphabetSize) by state's single-symbol and range edges: the
+// complement that Powerset(true) must wire to the dead state to make state's
+// transition function total.
+func uncoveredRanges(state *State, alphabetSize int) []Range {
+	if alphabetSize <= 0 {
+		return nil
+	}
+
+	var covered RangeSet
+	for sym := range state.transitions() {
+		if sym < 0 {
+			continue
+		}
+		covered = covered.Add(sym, sym)
+	}
+	for _, re := range state.ranges {
+		for _, r := range re.ranges {
+			covered = covered.Add(r.Lo, r.Hi)
+		}
+	}
+
+	var gaps []Range
+	next := 0
+	for _, r := range covered {
+		if r.Lo > next {
+			gaps = append(gaps, Range{next, r.Lo - 1})
+		}
+		if r.Hi+1 > next {
+			next = r.Hi + 1
+		}
+	}
+	if next < alphabetSize {
+		gaps = append(gaps, Range{next, alphabetSize - 1})
+	}
+	return gaps
+}
+
 // Reverse returns a NFA for the reverse language accepted by n.
 func (n *NFA) Reverse() (out *NFA) {
 	out = NewNFA()
@@ -239,6 +322,9 @@ func (n *NFA) Reverse() (out *NFA) {
 				a[to.Id()].NewEdge(sym, a[idFrom])
 			}
 		}
+		for _, re := range state.ranges {
+			a[re.next.Id()].NewRangeEdge(re.ranges, a[idFrom])
+		}
 	}
 
 	a[n.start.Id()].IsAccepting = true
@@ -290,6 +376,24 @@ type State struct {
 	nfa         *NFA
 	IsAccepting bool // Whether this state is an accepting one.
 	edges       transitions
+	ranges      []rangeEdge
+
+	// AcceptRule and AcceptPriority are meaningful only when IsAccepting
+	// is true. For a state that originates from NFA.Union, AcceptRule is
+	// the index, within that Union call, of the pattern this state came
+	// from, and AcceptPriority ranks it against other accepting states
+	// folded into the same DFA state by Powerset, lower values winning.
+	// NewAhoCorasick sets AcceptRule to the lowest matched pattern index
+	// and leaves AcceptPriority zero. Both are zero for other NFAs.
+	AcceptRule     int
+	AcceptPriority int
+
+	// MatchedPatterns is set by NewAhoCorasick: for a state reached after
+	// consuming some input, it lists, in no particular order, the index
+	// in patterns of every pattern ending at that point, including those
+	// reached only through the dictionary suffix chain. Nil for states
+	// not built by NewAhoCorasick.
+	MatchedPatterns []int
 }
 
 // Closure returns a state set consisting of s and all states reachable from s
@@ -316,8 +420,19 @@ func (s *State) closure() (c closure) {
 	return
 }
 
-func (s *State) edge(sym int) closure {
-	return s.transitions().edge(sym, false)
+// step returns the (unique, since s is assumed to be a DFA state) state
+// reached from s on sym via either a single-symbol or a range edge, or nil
+// if there is none.
+func step(s *State, sym int) *State {
+	for _, next := range s.Transitions().Get(sym).List() {
+		return next
+	}
+	for _, re := range s.RangeEdges() {
+		if re.Ranges.Has(sym) {
+			return re.Next
+		}
+	}
+	return nil
 }
 
 // Transitions returns the symbol -> closure projection of state s.
@@ -332,8 +447,23 @@ func (s *State) transitions() transitions {
 	return s.edges
 }
 
+// ε returns the states reachable from s via a single ε-class edge: a plain
+// Epsilon edge or a prioritized one added through PriorityEpsilon. Both are
+// always taken during closure computation, and the edge's priority is not
+// consulted here or in Powerset: accepting states are disambiguated by their
+// AcceptPriority field (see Powerset).
 func (s *State) ε() closure {
-	return s.edge(Epsilon)
+	c := closure{}
+	for sym, next := range s.transitions() {
+		if sym > Epsilon {
+			continue
+		}
+
+		for st := range next {
+			c[st] = struct{}{}
+		}
+	}
+	return c
 }
 
 // Id returns the state's zero based index.
@@ -343,13 +473,28 @@ func (s *State) Id() int {
 
 // NewEdge connects state s and state next by a new edge, labeled by sym. By
 // convention, passing sym == Epsilon is reserved to indicate adding of an ε
-// edge.
-//
-//TODO implement priorities for sym < Epsilon
+// edge, and sym values below Epsilon are reserved for prioritized ε edges
+// added through PriorityEpsilon.
 func (s *State) NewEdge(sym int, next *State) {
 	s.transitions().newEdge(sym, true, next)
 }
 
+// NewRangeEdge connects state s and state next by a new edge matching any
+// symbol in rs. Range edges coexist with, and are otherwise independent of,
+// the single-symbol edges added via NewEdge.
+func (s *State) NewRangeEdge(rs RangeSet, next *State) {
+	s.ranges = append(s.ranges, rangeEdge{rs, next})
+}
+
+// RangeEdges returns the range-labeled edges leaving s.
+func (s *State) RangeEdges() []RangeEdge {
+	r := make([]RangeEdge, len(s.ranges))
+	for i, re := range s.ranges {
+		r[i] = RangeEdge{re.ranges, re.next}
+	}
+	return r
+}
+
 var (
 	isAcceptingL = map[bool]string{true: "["}
 	isAcceptingR = map[bool]string{true: "]"}
@@ -387,6 +532,9 @@ func (s *State) String() string {
 		}
 		f.Format("\n")
 	}
+	for _, re := range s.ranges {
+		f.Format("%s -> [%d]\n", re.ranges, re.next.Id())
+	}
 	return b.String()
 }
 
@@ -0,0 +1,60 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+// PriorityEpsilon returns the symbol value for a prioritized ε edge: like a
+// plain Epsilon edge it is always followed during closure computation. The
+// priority is only encoded in the symbol value; Powerset does not read it,
+// and ranks accepting states by State.AcceptPriority instead, lower values
+// winning. NFA.Union sets that field to the same value as the priority of
+// the edge leading to each pattern.
+func PriorityEpsilon(priority int) int {
+	return Epsilon - 1 - priority
+}
+
+// Union returns a new NFA recognizing the union of the languages of n and
+// others, suitable as the input to Powerset when building a lexer: a fresh
+// start state connects to a private copy of n's start state and of each of
+// others' start states through a PriorityEpsilon edge, n taking priority 0
+// and others[i] taking priority i+1. Every accepting state copied from
+// pattern i is tagged with AcceptRule == i, so Powerset can later report,
+// for every DFA state, which pattern a match should be attributed to.
+func (n *NFA) Union(others ...*NFA) *NFA {
+	pats := append([]*NFA{n}, others...)
+	out := NewNFA()
+	start := out.NewState()
+	out.SetStart(start)
+	for rule, pat := range pats {
+		unionCopy(out, pat, rule, start)
+	}
+	return out
+}
+
+// unionCopy copies every state and edge of pat into out, tagging accepting
+// states with rule, and links start to the copy of pat's start state via a
+// PriorityEpsilon(rule) edge.
+func unionCopy(out *NFA, pat *NFA, rule int, start *State) {
+	m := make(map[*State]*State, pat.Len())
+	for _, s := range pat.List() {
+		m[s] = out.NewState()
+	}
+	for _, s := range pat.List() {
+		ns := m[s]
+		ns.IsAccepting = s.IsAccepting
+		if s.IsAccepting {
+			ns.AcceptRule = rule
+			ns.AcceptPriority = rule
+		}
+		for sym, tos := range s.edges {
+			for to := range tos {
+				ns.NewEdge(sym, m[to])
+			}
+		}
+		for _, re := range s.ranges {
+			ns.NewRangeEdge(re.ranges, m[re.next])
+		}
+	}
+	start.NewEdge(PriorityEpsilon(rule), m[pat.Start()])
+}
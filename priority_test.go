@@ -0,0 +1,76 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import "testing"
+
+// linear builds an NFA accepting exactly s, one byte per edge.
+func linear(s string) *NFA {
+	n := NewNFA()
+	cur := n.NewState()
+	n.SetStart(cur)
+	for i := 0; i < len(s); i++ {
+		next := n.NewState()
+		cur.NewEdge(int(s[i]), next)
+		cur = next
+	}
+	cur.IsAccepting = true
+	return n
+}
+
+func runDFA(start *State, s string) *State {
+	cur := start
+	for i := 0; i < len(s) && cur != nil; i++ {
+		cur = step(cur, int(s[i]))
+	}
+	return cur
+}
+
+func TestUnionAcceptRule(t *testing.T) {
+	// Two overlapping patterns matching the same input "if": the
+	// keyword (rule 0, higher priority) and a generic identifier (rule
+	// 1). The DFA must report the keyword's rule.
+	kw := linear("if")
+	ident := linear("if")
+	u := kw.Union(ident)
+	d := u.Powerset(false)
+
+	end := runDFA(d.Start(), "if")
+	if end == nil || !end.IsAccepting {
+		t.Fatal("expected \"if\" to be accepted")
+	}
+	if g, e := end.AcceptRule, 0; g != e {
+		t.Fatalf("AcceptRule = %d, want %d (the higher-priority rule)", g, e)
+	}
+}
+
+func TestPowersetAndHopcroftPreserveAcceptRule(t *testing.T) {
+	// MinimalDFA's AcceptRule isn't meaningful for Union-built,
+	// multi-rule NFAs (see its doc comment), so it is not checked here.
+	// Powerset and HopcroftMinimize must both report the rule of the
+	// pattern that matched, here rule 1 for "abc".
+	ident := linear("xyz")
+	kw := linear("abc")
+	u := ident.Union(kw) // ident is rule 0, kw is rule 1
+
+	want := runDFA(u.Powerset(false).Start(), "abc")
+	if want == nil || !want.IsAccepting || want.AcceptRule != 1 {
+		t.Fatalf("Powerset: expected \"abc\" accepted with AcceptRule 1, got %+v", want)
+	}
+
+	h := runDFA(u.HopcroftMinimize(false).Start(), "abc")
+	if h == nil || !h.IsAccepting || h.AcceptRule != 1 {
+		t.Fatalf("HopcroftMinimize: expected \"abc\" accepted with AcceptRule 1, got %+v", h)
+	}
+}
+
+func TestPriorityEpsilonBelowEpsilon(t *testing.T) {
+	if g := PriorityEpsilon(0); g >= Epsilon {
+		t.Fatalf("PriorityEpsilon(0) = %d, want < Epsilon (%d)", g, Epsilon)
+	}
+	if g, e := PriorityEpsilon(0), PriorityEpsilon(1); g <= e {
+		t.Fatalf("PriorityEpsilon(0) = %d should be > PriorityEpsilon(1) = %d", g, e)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Range is an inclusive interval of symbol values [Lo, Hi].
+type Range struct {
+	Lo, Hi int
+}
+
+// RangeSet is a set of symbol ranges. It is always kept sorted by Lo with
+// overlapping or adjacent ranges merged, so two RangeSets covering the same
+// symbols compare equal element by element.
+type RangeSet []Range
+
+// NewRangeSet returns a RangeSet covering exactly the given ranges.
+func NewRangeSet(ranges ...Range) RangeSet {
+	var rs RangeSet
+	for _, r := range ranges {
+		rs = rs.Add(r.Lo, r.Hi)
+	}
+	return rs
+}
+
+// Add inserts [lo, hi] (lo and hi may be given in either order) into rs,
+// merging it with any range it overlaps or touches, and returns the result.
+func (rs RangeSet) Add(lo, hi int) RangeSet {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	rs = append(rs, Range{lo, hi})
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Lo < rs[j].Lo })
+	out := rs[:0]
+	for _, r := range rs {
+		if n := len(out); n != 0 && r.Lo <= out[n-1].Hi+1 {
+			if r.Hi > out[n-1].Hi {
+				out[n-1].Hi = r.Hi
+			}
+			continue
+		}
+
+		out = append(out, r)
+	}
+	return out
+}
+
+// Has reports whether sym is covered by some range in rs.
+func (rs RangeSet) Has(sym int) bool {
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].Hi >= sym })
+	return i < len(rs) && rs[i].Lo <= sym
+}
+
+// hi returns the highest symbol covered by rs, or -1 if rs is empty.
+func (rs RangeSet) hi() int {
+	if len(rs) == 0 {
+		return -1
+	}
+	return rs[len(rs)-1].Hi
+}
+
+// String implements fmt.Stringer for debugging, etc.
+func (rs RangeSet) String() string {
+	var b []byte
+	for i, r := range rs {
+		if i != 0 {
+			b = append(b, ',')
+		}
+		if r.Lo == r.Hi {
+			b = append(b, fmt.Sprintf("%d", r.Lo)...)
+			continue
+		}
+
+		b = append(b, fmt.Sprintf("%d-%d", r.Lo, r.Hi)...)
+	}
+	return string(b)
+}
+
+// rangeEdge is a single range-labeled edge leaving some state.
+type rangeEdge struct {
+	ranges RangeSet
+	next   *State
+}
+
+// RangeEdge is the exported view of a range-labeled edge returned by
+// State.RangeEdges.
+type RangeEdge struct {
+	Ranges RangeSet
+	Next   *State
+}
+
+// rangeContrib is a range edge found while building a Powerset closure,
+// already resolved to the ε-closure of its target.
+type rangeContrib struct {
+	ranges RangeSet
+	next   closure
+}
+
+// partitionClass is one class of the coarsest partition computed by
+// partition: every symbol in [lo, hi] leads to the same target closure.
+type partitionClass struct {
+	lo, hi int
+	tgt    closure
+}
+
+// partition computes the coarsest partition of the alphabet induced by the
+// single-symbol edges in points and the range edges in ranges: every class
+// [lo, hi] it returns is either entirely inside or entirely outside each
+// input edge, so its target closure (the union of every next-closure whose
+// edge covers the class) is constant over the whole class.
+func partition(points transitions, ranges []rangeContrib) []partitionClass {
+	cuts := map[int]struct{}{}
+	for sym := range points {
+		cuts[sym] = struct{}{}
+		cuts[sym+1] = struct{}{}
+	}
+	for _, rc := range ranges {
+		for _, r := range rc.ranges {
+			cuts[r.Lo] = struct{}{}
+			cuts[r.Hi+1] = struct{}{}
+		}
+	}
+
+	sorted := make([]int, 0, len(cuts))
+	for c := range cuts {
+		sorted = append(sorted, c)
+	}
+	sort.Ints(sorted)
+
+	classes := make([]partitionClass, 0, len(sorted))
+	for i := 0; i+1 < len(sorted); i++ {
+		lo, hi := sorted[i], sorted[i+1]-1
+		tgt := closure{}
+		if c, ok := points[lo]; ok {
+			for st := range c {
+				tgt[st] = struct{}{}
+			}
+		}
+		for _, rc := range ranges {
+			if rc.ranges.Has(lo) {
+				for st := range rc.next {
+					tgt[st] = struct{}{}
+				}
+			}
+		}
+		classes = append(classes, partitionClass{lo, hi, tgt})
+	}
+	return classes
+}
+
+// ByteClasses maps every symbol in [0, Max] to an equivalence-class id such
+// that any two symbols sharing a class are indistinguishable by every edge
+// (literal or range) of the NFA it was computed from: a table-driven
+// matcher can index its transition table by class instead of by raw
+// symbol, collapsing e.g. a huge Unicode range edge down to a single
+// column.
+type ByteClasses struct {
+	Max     int   // highest symbol classified, inclusive.
+	Classes []int // Classes[sym] is the class id of sym, for 0 <= sym <= Max.
+	N       int   // number of distinct classes.
+}
+
+// Class returns the class id of sym, or -1 if sym is outside [0, c.Max].
+func (c ByteClasses) Class(sym int) int {
+	if sym < 0 || sym > c.Max {
+		return -1
+	}
+	return c.Classes[sym]
+}
+
+// ByteClasses computes the coarsest partition of [0, max] induced by every
+// literal and range edge of every state of n. A typical max is 255 for
+// byte-oriented machines or 0x10FFFF for rune-oriented ones.
+func (n *NFA) ByteClasses(max int) ByteClasses {
+	cuts := map[int]struct{}{0: {}}
+	if max+1 >= 0 {
+		cuts[max+1] = struct{}{}
+	}
+	for _, s := range n.List() {
+		for sym := range s.transitions() {
+			if sym < 0 || sym > max {
+				continue
+			}
+
+			cuts[sym] = struct{}{}
+			cuts[sym+1] = struct{}{}
+		}
+		for _, re := range s.ranges {
+			for _, r := range re.ranges {
+				if r.Lo <= max {
+					cuts[r.Lo] = struct{}{}
+				}
+				if r.Hi < max {
+					cuts[r.Hi+1] = struct{}{}
+				}
+			}
+		}
+	}
+
+	sorted := make([]int, 0, len(cuts))
+	for c := range cuts {
+		sorted = append(sorted, c)
+	}
+	sort.Ints(sorted)
+
+	classes := make([]int, max+1)
+	class := -1
+	for i := 0; i+1 < len(sorted); i++ {
+		lo, hi := sorted[i], sorted[i+1]-1
+		if lo > max {
+			break
+		}
+		if hi > max {
+			hi = max
+		}
+
+		class++
+		for sym := lo; sym <= hi; sym++ {
+			classes[sym] = class
+		}
+	}
+	return ByteClasses{Max: max, Classes: classes, N: class + 1}
+}
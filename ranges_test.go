@@ -0,0 +1,60 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsm
+
+import "testing"
+
+func TestRangeSet(t *testing.T) {
+	rs := NewRangeSet(Range{0, 2}, Range{5, 7})
+	rs = rs.Add(3, 4) // bridges the gap, merging all three into one range
+	if g, e := rs.String(), "0-7"; g != e {
+		t.Fatalf("got %q, want %q", g, e)
+	}
+
+	for sym := 0; sym <= 7; sym++ {
+		if !rs.Has(sym) {
+			t.Fatalf("%d: expected covered", sym)
+		}
+	}
+	if rs.Has(8) {
+		t.Fatal("8: expected not covered")
+	}
+}
+
+func TestPowersetRangeEdge(t *testing.T) {
+	n := NewNFA()
+	s0, s1 := n.NewState(), n.NewState()
+	s1.IsAccepting = true
+	s0.NewRangeEdge(NewRangeSet(Range{'0', '2'}), s1)
+
+	d := n.Powerset(false)
+	start := d.Start()
+	for _, sym := range []int{'0', '1', '2'} {
+		next := step(start, sym)
+		if next == nil || !next.IsAccepting {
+			t.Fatalf("sym %q: expected to reach an accepting state", sym)
+		}
+	}
+	if step(start, '3') != nil {
+		t.Fatal("sym '3': expected no transition")
+	}
+}
+
+func TestByteClasses(t *testing.T) {
+	n := NewNFA()
+	s0, s1 := n.NewState(), n.NewState()
+	s0.NewRangeEdge(NewRangeSet(Range{'0', '9'}), s1)
+
+	bc := n.ByteClasses(255)
+	if bc.Class('0') != bc.Class('5') || bc.Class('5') != bc.Class('9') {
+		t.Fatal("digits should share a class")
+	}
+	if bc.Class('0') == bc.Class('a') {
+		t.Fatal("a digit and a non-digit should not share a class")
+	}
+	if bc.N < 2 {
+		t.Fatalf("expected at least 2 classes, got %d", bc.N)
+	}
+}
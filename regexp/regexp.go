@@ -0,0 +1,233 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+
+Package regexp builds fsm.NFA machines from regular expressions.
+
+Patterns are parsed by the standard regexp/syntax package and lowered to an
+*fsm.NFA using Thompson's construction: every AST node compiles to a
+fragment with a single start and a single accept state, fragments are
+stitched together by ε edges and the accept state of the top level fragment
+becomes the NFA's only accepting state. The result is intended to be fed
+into NFA.Powerset or NFA.MinimalDFA to obtain a recognizer.
+
+Character classes (and OpAnyChar/OpAnyCharNotNL) are lowered to one
+fsm.RangeEdge per [lo,hi] pair rather than one edge per rune, so even large
+classes such as [^\n] or a Unicode category compile to a handful of edges.
+
+*/
+package regexp
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cznic/fsm"
+)
+
+// Compile parses pattern using regexp/syntax and returns an *fsm.NFA
+// recognizing the same language, built using Thompson's construction.
+func Compile(pattern string) (*fsm.NFA, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	n := fsm.NewNFA()
+	start, accept := compile(n, re)
+	n.SetStart(start)
+	accept.IsAccepting = true
+	return n, nil
+}
+
+// MustCompile is like Compile but panics if pattern cannot be parsed.
+func MustCompile(pattern string) *fsm.NFA {
+	n, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// compile lowers re into a new fragment of n and returns the fragment's
+// start and accept states.
+func compile(n *fsm.NFA, re *syntax.Regexp) (start, accept *fsm.State) {
+	switch re.Op {
+	case
+		syntax.OpEmptyMatch,
+		syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		// Zero-width assertions are not tracked by this construction;
+		// treat them as the empty match.
+		start = n.NewState()
+		accept = start
+		return
+
+	case syntax.OpLiteral:
+		return compileLiteral(n, re.Rune, re.Flags&syntax.FoldCase != 0)
+
+	case syntax.OpCharClass:
+		return compileRunes(n, re.Rune)
+
+	case syntax.OpAnyChar:
+		return compileRunes(n, []rune{0, utf8.MaxRune})
+
+	case syntax.OpAnyCharNotNL:
+		return compileRunes(n, []rune{0, '\n' - 1, '\n' + 1, utf8.MaxRune})
+
+	case syntax.OpCapture:
+		return compile(n, re.Sub[0])
+
+	case syntax.OpConcat:
+		return compileConcat(n, re.Sub)
+
+	case syntax.OpAlternate:
+		return compileAlternate(n, re.Sub)
+
+	case syntax.OpStar:
+		return compileStar(n, re.Sub[0])
+
+	case syntax.OpPlus:
+		return compilePlus(n, re.Sub[0])
+
+	case syntax.OpQuest:
+		return compileQuest(n, re.Sub[0])
+
+	case syntax.OpRepeat:
+		return compileRepeat(n, re.Sub[0], re.Min, re.Max)
+
+	default:
+		panic(fmt.Errorf("fsm/regexp: unsupported regexp operator %v", re.Op))
+	}
+}
+
+// compileLiteral builds a chain of two-state fragments, one per rune, ie. a
+// single path accepting exactly the string represented by runes. If fold is
+// set (the OpLiteral node had syntax.FoldCase, as "(?i)abc" does: the
+// parser does not pre-expand case-insensitive literals into char classes),
+// each hop instead matches every rune that case-folds to the same value.
+func compileLiteral(n *fsm.NFA, runes []rune, fold bool) (start, accept *fsm.State) {
+	start = n.NewState()
+	accept = start
+	for _, r := range runes {
+		next := n.NewState()
+		if fold {
+			accept.NewRangeEdge(foldRange(r), next)
+		} else {
+			accept.NewEdge(int(r), next)
+		}
+		accept = next
+	}
+	return
+}
+
+// foldRange returns the RangeSet of every rune that case-folds to the same
+// value as r, including r itself.
+func foldRange(r rune) fsm.RangeSet {
+	rs := fsm.NewRangeSet(fsm.Range{Lo: int(r), Hi: int(r)})
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		rs = rs.Add(int(f), int(f))
+	}
+	return rs
+}
+
+// compileRunes builds a single-hop fragment accepting any one rune in the
+// set of [lo,hi] pairs in ranges, one fsm.RangeEdge per pair regardless of
+// how many runes it spans.
+func compileRunes(n *fsm.NFA, ranges []rune) (start, accept *fsm.State) {
+	start = n.NewState()
+	accept = n.NewState()
+	for i := 0; i < len(ranges); i += 2 {
+		start.NewRangeEdge(fsm.NewRangeSet(fsm.Range{Lo: int(ranges[i]), Hi: int(ranges[i+1])}), accept)
+	}
+	return
+}
+
+// compileConcat links the fragments of subs end to end with ε edges.
+func compileConcat(n *fsm.NFA, subs []*syntax.Regexp) (start, accept *fsm.State) {
+	if len(subs) == 0 {
+		start = n.NewState()
+		accept = start
+		return
+	}
+
+	start, accept = compile(n, subs[0])
+	for _, sub := range subs[1:] {
+		s, a := compile(n, sub)
+		accept.NewEdge(fsm.Epsilon, s)
+		accept = a
+	}
+	return
+}
+
+// compileAlternate builds a new start/accept pair with ε edges fanning out
+// to, and back in from, every branch's own fragment.
+func compileAlternate(n *fsm.NFA, subs []*syntax.Regexp) (start, accept *fsm.State) {
+	start = n.NewState()
+	accept = n.NewState()
+	for _, sub := range subs {
+		s, a := compile(n, sub)
+		start.NewEdge(fsm.Epsilon, s)
+		a.NewEdge(fsm.Epsilon, accept)
+	}
+	return
+}
+
+// compileStar wraps the body fragment so it can be skipped, entered once, or
+// repeated, via a fresh start/accept pair.
+func compileStar(n *fsm.NFA, sub *syntax.Regexp) (start, accept *fsm.State) {
+	bodyStart, bodyAccept := compile(n, sub)
+	start = n.NewState()
+	accept = n.NewState()
+	start.NewEdge(fsm.Epsilon, bodyStart)
+	start.NewEdge(fsm.Epsilon, accept)
+	bodyAccept.NewEdge(fsm.Epsilon, bodyStart)
+	bodyAccept.NewEdge(fsm.Epsilon, accept)
+	return
+}
+
+// compilePlus is compileStar without the skip edge out of start: the body
+// must be entered at least once.
+func compilePlus(n *fsm.NFA, sub *syntax.Regexp) (start, accept *fsm.State) {
+	start, bodyAccept := compile(n, sub)
+	accept = n.NewState()
+	bodyAccept.NewEdge(fsm.Epsilon, start)
+	bodyAccept.NewEdge(fsm.Epsilon, accept)
+	return
+}
+
+// compileQuest is compileStar without the loop-back edge: the body may be
+// entered at most once.
+func compileQuest(n *fsm.NFA, sub *syntax.Regexp) (start, accept *fsm.State) {
+	bodyStart, bodyAccept := compile(n, sub)
+	start = n.NewState()
+	accept = n.NewState()
+	start.NewEdge(fsm.Epsilon, bodyStart)
+	start.NewEdge(fsm.Epsilon, accept)
+	bodyAccept.NewEdge(fsm.Epsilon, accept)
+	return
+}
+
+// compileRepeat unrolls sub{min,max} into min mandatory copies followed by
+// either (max-min) optional copies, or, when max == -1 (sub{min,}), a single
+// trailing star.
+func compileRepeat(n *fsm.NFA, sub *syntax.Regexp, min, max int) (start, accept *fsm.State) {
+	var subs []*syntax.Regexp
+	for i := 0; i < min; i++ {
+		subs = append(subs, sub)
+	}
+	switch {
+	case max == -1:
+		subs = append(subs, &syntax.Regexp{Op: syntax.OpStar, Sub: []*syntax.Regexp{sub}})
+	case max > min:
+		for i := min; i < max; i++ {
+			subs = append(subs, &syntax.Regexp{Op: syntax.OpQuest, Sub: []*syntax.Regexp{sub}})
+		}
+	}
+	return compileConcat(n, subs)
+}
@@ -0,0 +1,122 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package regexp
+
+import (
+	"testing"
+
+	"github.com/cznic/fsm"
+)
+
+// accepts runs n, treating it as an NFA with byte-valued edge symbols, over
+// s and reports whether it ends in an accepting state.
+func accepts(n *fsm.NFA, s string) bool {
+	cur := n.Start().Closure()
+	for i := 0; i < len(s); i++ {
+		sym := int(s[i])
+		next := fsm.NewClosure()
+		for _, state := range cur.List() {
+			for _, to := range state.Transitions().Get(sym).List() {
+				for _, c := range to.Closure().List() {
+					next.Include(c)
+				}
+			}
+			for _, re := range state.RangeEdges() {
+				if !re.Ranges.Has(sym) {
+					continue
+				}
+				for _, c := range re.Next.Closure().List() {
+					next.Include(c)
+				}
+			}
+		}
+		cur = next
+	}
+	for _, state := range cur.List() {
+		if state.IsAccepting {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		pattern string
+		yes     []string
+		no      []string
+	}{
+		{"abc", []string{"abc"}, []string{"ab", "abcd", ""}},
+		{"a|b", []string{"a", "b"}, []string{"c", "ab"}},
+		{"ab*c", []string{"ac", "abc", "abbbc"}, []string{"ab", "abcc"}},
+		{"ab+c", []string{"abc", "abbc"}, []string{"ac"}},
+		{"ab?c", []string{"ac", "abc"}, []string{"abbc"}},
+		{"a{2,3}", []string{"aa", "aaa"}, []string{"a", "aaaa"}},
+		{"[a-c]d", []string{"ad", "bd", "cd"}, []string{"dd"}},
+		{"(?i)abc", []string{"abc", "ABC", "AbC", "aBc"}, []string{"abd", "ab"}},
+	}
+	for _, c := range cases {
+		n, err := Compile(c.pattern)
+		if err != nil {
+			t.Fatalf("%q: %v", c.pattern, err)
+		}
+
+		for _, s := range c.yes {
+			if !accepts(n, s) {
+				t.Errorf("%q: expected %q to match", c.pattern, s)
+			}
+		}
+		for _, s := range c.no {
+			if accepts(n, s) {
+				t.Errorf("%q: expected %q not to match", c.pattern, s)
+			}
+		}
+	}
+}
+
+// TestCompileLargeClassesAreBounded guards against regressing into one NFA
+// edge per rune for wide classes: "." and "[^\n]" both span almost all of
+// Unicode, and a Unicode category can span tens of thousands of runes.
+func TestCompileLargeClassesAreBounded(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		maxEdges int
+	}{
+		{".", 16},
+		{`[^\n]`, 16},
+		// \p{L} (Unicode letters) is itself hundreds of disjoint
+		// ranges, so only hundreds of edges, one per range, not the
+		// ~100k+ runes it covers.
+		{`\p{L}`, 2000},
+	}
+	for _, c := range cases {
+		n, err := Compile(c.pattern)
+		if err != nil {
+			t.Fatalf("%q: %v", c.pattern, err)
+		}
+
+		edges := 0
+		for _, s := range n.List() {
+			edges += len(s.RangeEdges())
+			edges += len(s.Transitions().List())
+		}
+		if edges > c.maxEdges {
+			t.Fatalf("%q: compiled to %d edges, want <= %d (one per range, not one per rune)", c.pattern, edges, c.maxEdges)
+		}
+	}
+
+	if !accepts(MustCompile(`[^\n]`), "x") {
+		t.Fatal(`[^\n]: expected "x" to match`)
+	}
+	if accepts(MustCompile(`[^\n]`), "\n") {
+		t.Fatal(`[^\n]: expected "\n" not to match`)
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	if _, err := Compile("("); err == nil {
+		t.Fatal("expected an error for an unbalanced paren")
+	}
+}